@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/droyo/gogive/auth"
+)
+
+// adminServer implements the operator-facing admin API, served on a
+// separate listener from the public vanity/proxy handler. Every
+// request must present HTTP Basic credentials matching User and Pass,
+// which are distinct from any netrc entry: netrc credentials
+// authenticate gogive as a client to upstream mod proxies, and must
+// never double as the admin API's own inbound credential, or anyone
+// who already knows an upstream's credentials could authenticate to
+// the admin API by sending the matching Host header.
+type adminServer struct {
+	srv        *Server
+	User, Pass string
+}
+
+var metricsHandler = promhttp.Handler()
+
+func (a *adminServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !a.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="gogive-admin"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/_admin/reload":
+		a.reload(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/_admin/routes":
+		a.routes(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/metrics":
+		metricsHandler.ServeHTTP(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (a *adminServer) authorized(r *http.Request) bool {
+	return auth.CheckBasicAuth(r, a.User, a.Pass)
+}
+
+// reload forces an immediate config reload, reporting any error back
+// to the caller instead of just logging it, so an operator can tell
+// whether a reload actually took effect.
+func (a *adminServer) reload(w http.ResponseWriter, r *http.Request) {
+	resp := make(chan error, 1)
+	a.srv.reload <- resp
+	if err := <-resp; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a *adminServer) routes(w http.ResponseWriter, r *http.Request) {
+	routes := <-a.srv.Routes
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(routes.Entries())
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminServeHTTPRequiresAuth(t *testing.T) {
+	s := NewServer("")
+	a := &adminServer{srv: s, User: "admin", Pass: "hunter2"}
+
+	req := httptest.NewRequest("GET", "/_admin/routes", nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if got := w.Header().Get("WWW-Authenticate"); got == "" {
+		t.Error("WWW-Authenticate header not set on 401 response")
+	}
+
+	req.SetBasicAuth("admin", "wrong")
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status with wrong password = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAdminRoutes(t *testing.T) {
+	r := newRouter()
+	must(t, r.Add("/foo", Source{Vcs: "git", Url: "https://example.com/foo.git"}))
+
+	s := NewServer("")
+	serveRoutes(t, s, r)
+	a := &adminServer{srv: s, User: "admin", Pass: "hunter2"}
+
+	req := httptest.NewRequest("GET", "/_admin/routes", nil)
+	req.SetBasicAuth("admin", "hunter2")
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var entries []RouteEntry
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Path != "/foo" {
+		t.Errorf("entries = %+v, want a single /foo entry", entries)
+	}
+}
+
+func TestAdminReloadPropagatesNewRoutes(t *testing.T) {
+	r1 := newRouter()
+	must(t, r1.Add("/foo", Source{Vcs: "git", Url: "https://example.com/foo.git"}))
+	r2 := newRouter()
+	must(t, r2.Add("/foo", Source{Vcs: "git", Url: "https://example.com/foo-v2.git"}))
+
+	s := NewServer("")
+	current := r1
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		for {
+			select {
+			case s.Routes <- current:
+			case resp := <-s.reload:
+				current = r2
+				resp <- nil
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	a := &adminServer{srv: s, User: "admin", Pass: "hunter2"}
+
+	req := httptest.NewRequest("POST", "/_admin/reload", nil)
+	req.SetBasicAuth("admin", "hunter2")
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("reload status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	req = httptest.NewRequest("GET", "/foo?go-get=1", nil)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), "foo-v2.git") {
+		t.Errorf("body = %q, want it to reflect the reloaded route", w.Body.String())
+	}
+}
@@ -0,0 +1,136 @@
+// Package auth supplies credentials, read from a netrc file, for the
+// outbound HTTP requests gogive makes on its own behalf (such as
+// module proxy fallback requests). It mirrors the split the go command
+// itself makes between its web and auth packages: auth looks up
+// credentials for a host and, through Transport, attaches them to a
+// request that a wrapped RoundTripper then sends. CheckBasicAuth
+// separately checks the HTTP Basic credentials of an inbound request.
+package auth
+
+import (
+	"bufio"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Netrc holds the machine credentials parsed out of a netrc file.
+type Netrc struct {
+	machines map[string]machine
+}
+
+type machine struct {
+	login, password string
+}
+
+// Load reads the netrc file named by the NETRC environment variable,
+// or ~/.netrc if it is unset. A missing file is not an error; it
+// results in a Netrc with no credentials.
+func Load() (*Netrc, error) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return &Netrc{machines: map[string]machine{}}, nil
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &Netrc{machines: map[string]machine{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parse(f)
+}
+
+func parse(f *os.File) (*Netrc, error) {
+	n := &Netrc{machines: map[string]machine{}}
+	scanner := bufio.NewScanner(f)
+	scanner.Split(bufio.ScanWords)
+
+	var host string
+	var m machine
+	for scanner.Scan() {
+		switch tok := scanner.Text(); tok {
+		case "machine":
+			if host != "" {
+				n.machines[host] = m
+			}
+			if !scanner.Scan() {
+				return n, scanner.Err()
+			}
+			host, m = scanner.Text(), machine{}
+		case "login":
+			if scanner.Scan() {
+				m.login = scanner.Text()
+			}
+		case "password":
+			if scanner.Scan() {
+				m.password = scanner.Text()
+			}
+		}
+	}
+	if host != "" {
+		n.machines[host] = m
+	}
+	return n, scanner.Err()
+}
+
+// Credentials returns the login and password configured for host, if
+// any.
+func (n *Netrc) Credentials(host string) (login, password string, ok bool) {
+	if n == nil {
+		return "", "", false
+	}
+	m, ok := n.machines[host]
+	if !ok {
+		return "", "", false
+	}
+	return m.login, m.password, true
+}
+
+// Transport adds HTTP Basic credentials from a Netrc to outbound
+// requests, keyed by the request's host. It wraps another
+// http.RoundTripper, or http.DefaultTransport if Base is nil.
+type Transport struct {
+	Netrc *Netrc
+	Base  http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if login, password, ok := t.Netrc.Credentials(req.URL.Hostname()); ok {
+		req = req.Clone(req.Context())
+		req.SetBasicAuth(login, password)
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// equalConstantTime reports whether a and b hold the same bytes,
+// without leaking their length of match through timing.
+func equalConstantTime(a, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	var v byte
+	for i := 0; i < len(a); i++ {
+		v |= a[i] ^ b[i]
+	}
+	return v == 0
+}
+
+// CheckBasicAuth reports whether r carries HTTP Basic credentials
+// matching user and pass.
+func CheckBasicAuth(r *http.Request, user, pass string) bool {
+	u, p, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	return equalConstantTime(u, user) && equalConstantTime(p, pass)
+}
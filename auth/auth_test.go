@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "netrc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.WriteString("machine example.com login alice password s3cret\n")
+	if _, err := f.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	login, pass, ok := n.Credentials("example.com")
+	if !ok || login != "alice" || pass != "s3cret" {
+		t.Fatalf("Credentials(example.com) = %q, %q, %v", login, pass, ok)
+	}
+	if _, _, ok := n.Credentials("unknown.example.com"); ok {
+		t.Fatal("Credentials(unknown.example.com) returned ok = true")
+	}
+}
+
+func TestCheckBasicAuth(t *testing.T) {
+	r, err := http.NewRequest("GET", "http://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetBasicAuth("bob", "hunter2")
+	if !CheckBasicAuth(r, "bob", "hunter2") {
+		t.Fatal("CheckBasicAuth rejected matching credentials")
+	}
+	if CheckBasicAuth(r, "bob", "wrong") {
+		t.Fatal("CheckBasicAuth accepted wrong password")
+	}
+}
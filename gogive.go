@@ -3,7 +3,7 @@ gogive is a tool to serve vanity import paths for Go programs.
 
 Usage:
 
-	gogive [-a addr] file
+	gogive [-a addr] [-admin addr -admin-user user -admin-pass pass] file
 
 gogive will bind to the address specified by addr, or :9625 if
 none is specified, and serve HTTP requests issued by the go
@@ -19,9 +19,74 @@ html page with the following meta tag:
 
 	<meta name="go-import" content="example.com/path vcs vcsrepo">
 
+A line may optionally carry source browsing information, consumed by
+gddo/pkg.go.dev to build "View Source" links, by appending a fourth
+field of the form source=<home> followed by a directory URL template
+and a file URL template:
+
+	/path vcs vcsrepo source=<home> <dirTmpl> <fileTmpl>
+
+The directory template may use the placeholder {dir}, and the file
+template may use {dir}, {file} and {line}. When present, gogive emits
+an additional meta tag:
+
+	<meta name="go-source" content="example.com/path <home> <dirTmpl> <fileTmpl>">
+
 Note that gogive will use the value of the Host: header in the HTTP request
 in the output HTML. HTTP requests that do not contain the "go-get"
 query parameter will be redirected to godoc.org.
+
+Routes are matched by longest prefix, so if both /foo and /foo/bar are
+configured, a request for /foo/bar/baz is served by the /foo/bar
+route. The final path segment may instead be a "*" wildcard, matching
+any single segment and substituting it for "{1}" in vcsrepo:
+
+	/user/* vcs https://git.example.com/{1}.git
+
+A route may instead be declared as a module proxy fallback, with "mod"
+in place of vcs and a comma-separated list of upstream GOPROXY URLs in
+place of vcsrepo:
+
+	/path mod https://proxy.corp,https://proxy.golang.org,direct
+
+Requests under /path that match the GOPROXY protocol (/@v/list,
+/@v/<version>.info|.mod|.zip, /@latest) are answered by trying each
+upstream in turn, moving on to the next only on a 404 or 410 response,
+as documented at https://golang.org/ref/mod#goproxy-protocol. The
+pseudo-upstream "direct" always answers not found, since gogive does
+not fetch modules from VCS directly. Any other request under such a
+route, with or without go-get=1, gets a 404 rather than a go-import
+page or a redirect. Outbound requests gogive makes for
+such a route are authenticated using credentials from the netrc file
+named by $NETRC, or ~/.netrc if unset.
+
+A route of any kind may be marked private by appending "private" and a
+user:pass pair to its line:
+
+	/path vcs vcsrepo private alice:s3cret
+
+A private route only returns its go-import (and go-source) meta tags
+to requests presenting matching HTTP Basic credentials; all other
+requests get a 404, the same response as for a path with no route at
+all, so the existence of a private path is never leaked to the
+unauthenticated.
+
+The config file is reloaded whenever it changes on disk, on SIGHUP, or
+on a request to the admin API. The admin API is disabled by default;
+passing -admin addr starts it on a separate listener, guarded by its
+own -admin-user and -admin-pass credentials, required on every admin
+request and unrelated to the netrc credentials used for outbound mod
+proxy requests. It serves:
+
+	POST /_admin/reload   reload the config file now
+	GET  /_admin/routes   the routes currently loaded, as JSON
+	GET  /metrics         Prometheus metrics
+
+Every request is logged as structured output via log/slog, recording
+the method, path, matched route, host, whether go-get=1 was set, and
+the response status. The same fields back the gogive_requests_total
+counter exposed at /metrics, alongside a gogive_routes gauge tracking
+how many routes are currently loaded.
 */
 package main
 
@@ -31,84 +96,163 @@ import (
 	"fmt"
 	"html/template"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/droyo/gogive/auth"
+	"github.com/droyo/gogive/proxy"
 )
 
 var (
-	addr = flag.String("a", ":9625", "Address to listen on")
+	addr      = flag.String("a", ":9625", "Address to listen on")
+	adminAddr = flag.String("admin", "", "Address for the admin API to listen on (disabled if empty)")
+	adminUser = flag.String("admin-user", "", "Username required to authenticate to the admin API")
+	adminPass = flag.String("admin-pass", "", "Password required to authenticate to the admin API")
 )
 
 var pageTmpl = template.Must(template.New("HTML").Parse(
 	`<html>
 	<head>
 		<meta name="go-import" content="{{.Host}}{{.Path}} {{.Vcs}} {{.Url}}">
-	</head>
+		{{if .Home}}<meta name="go-source" content="{{.Host}}{{.Path}} {{.Home}} {{.DirTmpl}} {{.FileTmpl}}">
+		{{end}}</head>
 	<body></body>
 </html>`))
 
 type Source struct {
 	Vcs, Url string
-}
 
-type Router map[string]Source
+	// Home, DirTmpl and FileTmpl are optional and, when set, are
+	// used to emit a go-source meta tag alongside go-import. Home
+	// is the project home page; DirTmpl and FileTmpl are URL
+	// templates understood by gddo/pkg.go.dev, using the
+	// placeholders {dir}, {file} and {line}.
+	Home, DirTmpl, FileTmpl string
+
+	// Proxy is set when Vcs is "mod", in which case Url held a
+	// comma-separated list of upstream module proxy URLs and the
+	// route serves the GOPROXY protocol instead of a go-import tag.
+	Proxy *proxy.Proxy
+
+	// Private marks a route as requiring HTTP Basic credentials,
+	// checked against BasicUser and BasicPass, before any response
+	// revealing the route's existence is served.
+	Private   bool
+	BasicUser string
+	BasicPass string
+}
 
 type Server struct {
 	config string
-	Routes chan Router
+	netrc  *auth.Netrc
+	Routes chan *Router
+
+	// reload carries forced-reload requests from the admin API; each
+	// request is answered on the channel it sends.
+	reload chan chan error
+
+	// Logger records each request. It defaults to slog.Default() and
+	// may be replaced to send gogive's logs elsewhere.
+	Logger *slog.Logger
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	route := ""
+	var goget bool
+	defer func() {
+		s.logger().Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"host", r.Host,
+			"route", route,
+			"goget", goget,
+			"status", sw.status)
+		requestsTotal.WithLabelValues(route, strconv.Itoa(sw.status), strconv.FormatBool(goget)).Inc()
+	}()
+
 	var match struct {
 		Source
 		Host string
 		Path string
 	}
 	if r.Method != "GET" {
-		http.Error(w, "Method Not Allowed", 405)
+		http.Error(sw, "Method Not Allowed", 405)
 		return
 	}
+	goget = r.FormValue("go-get") == "1"
 	routes := <-s.Routes
 
-	if src, root, ok := routes.findPath(r.URL.Path); !ok {
-		http.Error(w, "Not Found", 404)
+	src, root, ok := routes.findPath(r.URL.Path)
+	if !ok {
+		http.Error(sw, "Not Found", 404)
+		return
+	}
+	route = root
+	if src.Private && !auth.CheckBasicAuth(r, src.BasicUser, src.BasicPass) {
+		// Respond as if the route didn't exist at all, rather than
+		// 401, so an unauthenticated client can't tell a private
+		// path apart from one that was never configured.
+		http.Error(sw, "Not Found", 404)
 		return
-	} else {
-		match.Source = src
-		match.Host = r.Host
-		match.Path = root
 	}
-	if r.FormValue("go-get") != "1" {
+	if src.Proxy != nil {
+		rest := strings.TrimPrefix(r.URL.Path, root)
+		if !proxy.IsRequest(rest) {
+			http.Error(sw, "Not Found", 404)
+			return
+		}
+		r.URL.Path = rest
+		src.Proxy.ServeHTTP(sw, r)
+		return
+	}
+	match.Source = src
+	match.Host = r.Host
+	match.Path = root
+	if !goget {
 		// if this request is not coming from the go tool, redirect
 		// to godoc.org
-		http.Redirect(w, r, "http://godoc.org/"+r.Host+r.URL.Path, http.StatusSeeOther)
+		http.Redirect(sw, r, "http://godoc.org/"+r.Host+r.URL.Path, http.StatusSeeOther)
 		return
 	}
-	if err := pageTmpl.Execute(w, match); err != nil {
-		log.Print(err)
+	if err := pageTmpl.Execute(sw, match); err != nil {
+		s.logger().Error("rendering page template", "error", err)
 	}
 }
 
-func (r Router) findPath(path string) (Source, string, bool) {
-	nodes := strings.Split(path, "/")
-	for len(nodes) > 0 {
-		path := strings.Join(nodes, "/")
-		if src, ok := r[path]; ok {
-			return src, path, true
-		}
-		nodes = nodes[:len(nodes)-1]
+func (s *Server) logger() *slog.Logger {
+	if s.Logger != nil {
+		return s.Logger
 	}
-	return Source{}, "", false
+	return slog.Default()
+}
+
+// statusWriter records the status code passed to WriteHeader, so it
+// can be included in request logs and metrics after the handler
+// returns.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
 }
 
 func main() {
 	log.SetFlags(0)
 	log.SetPrefix("")
 	flag.Usage = func() {
-		log.Printf("Usage: %s [-a addr] config\n", os.Args[0])
+		log.Printf("Usage: %s [-a addr] [-admin addr -admin-user user -admin-pass pass] config\n", os.Args[0])
 		os.Exit(2)
 	}
 	flag.Parse()
@@ -117,13 +261,33 @@ func main() {
 		flag.Usage()
 	}
 
+	nr, err := auth.Load()
+	if err != nil {
+		log.Print(err)
+		nr = nil
+	}
+
 	s := NewServer(flag.Arg(0))
+	s.netrc = nr
 	srv := &http.Server{
 		Handler: s,
 		Addr:    *addr,
 	}
 	go srv.ListenAndServe()
 	log.Print("Listening on ", *addr)
+
+	if *adminAddr != "" {
+		if *adminUser == "" || *adminPass == "" {
+			log.Fatal("-admin requires -admin-user and -admin-pass to be set")
+		}
+		admin := &http.Server{
+			Handler: &adminServer{srv: s, User: *adminUser, Pass: *adminPass},
+			Addr:    *adminAddr,
+		}
+		go admin.ListenAndServe()
+		log.Print("Admin API listening on ", *adminAddr)
+	}
+
 	if err := s.loadConfig(); err != nil {
 		log.Fatal(err)
 	}
@@ -132,39 +296,79 @@ func main() {
 func NewServer(config string) *Server {
 	return &Server{
 		config: config,
-		Routes: make(chan Router),
+		Routes: make(chan *Router),
+		reload: make(chan chan error),
 	}
 }
 
 // runs in its own goroutine.
 func (srv *Server) loadConfig() error {
-	r, err := NewRouter(srv.config)
+	r, err := NewRouter(srv.config, srv.netrc)
 	if err != nil {
 		return err
 	}
-	sig := make(chan os.Signal)
+	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGHUP)
 
+	watcher, err := fsnotify.NewWatcher()
+	var events chan fsnotify.Event
+	var watchErrs chan error
+	if err != nil {
+		// fsnotify is best-effort; SIGHUP and /_admin/reload still
+		// work without it.
+		srv.logger().Error("starting config watcher", "error", err)
+	} else {
+		defer watcher.Close()
+		if err := watcher.Add(filepath.Dir(srv.config)); err != nil {
+			srv.logger().Error("watching config directory", "error", err)
+		}
+		events, watchErrs = watcher.Events, watcher.Errors
+	}
+
+	routesGauge.Set(float64(len(r.Entries())))
+	reload := func() error {
+		nr, err := NewRouter(srv.config, srv.netrc)
+		if err != nil {
+			srv.logger().Error("reloading config", "error", err, "file", srv.config)
+			return err
+		}
+		r = nr
+		routesGauge.Set(float64(len(r.Entries())))
+		srv.logger().Info("config reloaded", "file", srv.config, "routes", len(r.Entries()))
+		return nil
+	}
+
 	for {
 		select {
 		case srv.Routes <- r:
 		case <-sig:
-			if nr, err := NewRouter(srv.config); err != nil {
-				log.Print(err)
-			} else {
-				r = nr
+			reload()
+		case ev, ok := <-events:
+			if ok && filepath.Clean(ev.Name) == filepath.Clean(srv.config) && (ev.Has(fsnotify.Write) || ev.Has(fsnotify.Create)) {
+				reload()
+			}
+		case err, ok := <-watchErrs:
+			if ok {
+				srv.logger().Error("watching config file", "error", err)
 			}
+		case resp := <-srv.reload:
+			resp <- reload()
 		}
 	}
 }
 
-func NewRouter(filename string) (Router, error) {
-	r := make(Router)
+// NewRouter reads filename and builds a Router from it. netrc supplies
+// credentials for any outbound requests routes of type mod perform
+// against their upstream proxies; it may be nil.
+func NewRouter(filename string, netrc *auth.Netrc) (*Router, error) {
+	r := newRouter()
 
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
+	defer file.Close()
+
 	n := 0
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
@@ -176,13 +380,37 @@ func NewRouter(filename string) (Router, error) {
 		if len(fields) == 0 {
 			continue
 		}
-		if len(fields) != 3 {
+
+		var private bool
+		var basicUser, basicPass string
+		if len(fields) >= 2 && fields[len(fields)-2] == "private" {
+			user, pass, ok := strings.Cut(fields[len(fields)-1], ":")
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: expected private user:pass, got %s", filename, n, fields[len(fields)-1])
+			}
+			private, basicUser, basicPass = true, user, pass
+			fields = fields[:len(fields)-2]
+		}
+
+		if len(fields) != 3 && len(fields) != 6 {
 			continue
 		}
-		if _, ok := r[fields[0]]; ok {
-			return nil, fmt.Errorf("%s:%d: duplicate entry %s", filename, n, fields[0])
+		src := Source{Vcs: fields[1], Url: fields[2], Private: private, BasicUser: basicUser, BasicPass: basicPass}
+		if fields[1] == "mod" {
+			p := proxy.New(strings.Split(fields[2], ","))
+			p.Client = &http.Client{Transport: &auth.Transport{Netrc: netrc}}
+			src.Proxy = p
+		} else if len(fields) == 6 {
+			if !strings.HasPrefix(fields[3], "source=") {
+				return nil, fmt.Errorf("%s:%d: expected source=<home>, got %s", filename, n, fields[3])
+			}
+			src.Home = strings.TrimPrefix(fields[3], "source=")
+			src.DirTmpl = fields[4]
+			src.FileTmpl = fields[5]
+		}
+		if err := r.Add(fields[0], src); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, n, err)
 		}
-		r[fields[0]] = Source{fields[1], fields[2]}
 	}
 	return r, scanner.Err()
 }
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serveRoutes feeds r to s.Routes for as long as the test runs, mimicking
+// the loop loadConfig runs in production.
+func serveRoutes(t *testing.T, s *Server, r *Router) {
+	t.Helper()
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		for {
+			select {
+			case s.Routes <- r:
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// writeConfig writes lines to a temp config file and returns its path.
+func writeConfig(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "gogive.conf")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// renderPage runs src through pageTmpl the way ServeHTTP does, and
+// returns the resulting HTML.
+func renderPage(t *testing.T, host, path string, src Source) string {
+	t.Helper()
+	var match struct {
+		Source
+		Host string
+		Path string
+	}
+	match.Source = src
+	match.Host = host
+	match.Path = path
+	var buf bytes.Buffer
+	if err := pageTmpl.Execute(&buf, match); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestNewRouterEmitsGoSourceTag(t *testing.T) {
+	config := writeConfig(t,
+		"/plain git https://example.com/plain.git",
+		"/src git https://example.com/src.git source=https://github.com/example/src /src/{dir} /src/{file}#L{line}",
+	)
+	r, err := NewRouter(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, _, ok := r.findPath("/plain")
+	if !ok {
+		t.Fatal("findPath(/plain) did not match")
+	}
+	if got := renderPage(t, "example.com", "/plain", plain); strings.Contains(got, "go-source") {
+		t.Errorf("rendered page for a 3-field line contains a go-source tag:\n%s", got)
+	}
+
+	src, _, ok := r.findPath("/src")
+	if !ok {
+		t.Fatal("findPath(/src) did not match")
+	}
+	if src.Home != "https://github.com/example/src" {
+		t.Errorf("Home = %q, want https://github.com/example/src", src.Home)
+	}
+	if src.DirTmpl != "/src/{dir}" || src.FileTmpl != "/src/{file}#L{line}" {
+		t.Errorf("DirTmpl, FileTmpl = %q, %q, want /src/{dir}, /src/{file}#L{line}", src.DirTmpl, src.FileTmpl)
+	}
+	got := renderPage(t, "example.com", "/src", src)
+	want := `<meta name="go-source" content="example.com/src https://github.com/example/src /src/{dir} /src/{file}#L{line}">`
+	if !strings.Contains(got, want) {
+		t.Errorf("rendered page does not contain %q:\n%s", want, got)
+	}
+}
+
+func TestNewRouterRejectsBadSourceField(t *testing.T) {
+	config := writeConfig(t,
+		"/src git https://example.com/src.git nosource=https://github.com/example/src /src/{dir} /src/{file}",
+	)
+	if _, err := NewRouter(config, nil); err == nil {
+		t.Fatal("NewRouter with a malformed source field returned nil error, want error")
+	}
+}
+
+func TestServeHTTPPrivateRoute(t *testing.T) {
+	r := newRouter()
+	must(t, r.Add("/priv", Source{
+		Vcs:       "git",
+		Url:       "https://example.com/priv.git",
+		Private:   true,
+		BasicUser: "alice",
+		BasicPass: "s3cret",
+	}))
+
+	s := NewServer("")
+	serveRoutes(t, s, r)
+
+	cases := []struct {
+		name       string
+		goget      bool
+		user, pass string
+		setAuth    bool
+		wantStatus int
+	}{
+		{"missing credentials", true, "", "", false, 404},
+		{"wrong credentials", true, "alice", "wrong", true, 404},
+		{"correct credentials", true, "alice", "s3cret", true, 200},
+		{"missing credentials, no go-get", false, "", "", false, 404},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			target := "/priv"
+			if c.goget {
+				target += "?go-get=1"
+			}
+			req := httptest.NewRequest("GET", target, nil)
+			if c.setAuth {
+				req.SetBasicAuth(c.user, c.pass)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+			if w.Code != c.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, c.wantStatus)
+			}
+		})
+	}
+}
+
+func TestServeHTTPPrivateRouteNotRedirected(t *testing.T) {
+	// A private route's non-go-get path normally redirects to godoc.org;
+	// confirm the private check still gates it rather than letting the
+	// redirect reveal the route exists.
+	r := newRouter()
+	must(t, r.Add("/priv", Source{
+		Vcs:       "git",
+		Url:       "https://example.com/priv.git",
+		Private:   true,
+		BasicUser: "alice",
+		BasicPass: "s3cret",
+	}))
+
+	s := NewServer("")
+	serveRoutes(t, s, r)
+
+	req := httptest.NewRequest("GET", "/priv", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+
+	if w.Code == http.StatusSeeOther {
+		t.Fatal("unauthenticated request to a private route was redirected instead of gated")
+	}
+	if w.Code != 404 {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+}
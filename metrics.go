@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// requestsTotal counts every request ServeHTTP handles, labeled
+	// by the matched route's import root (empty for no match), the
+	// response status code, and whether go-get=1 was set.
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gogive_requests_total",
+		Help: "Total HTTP requests served, by matched route, response status and go-get.",
+	}, []string{"route", "status", "goget"})
+
+	// routesGauge reflects the number of routes in the most recently
+	// loaded config, so a reload that silently drops routes (e.g. a
+	// config typo) is visible without reading logs.
+	routesGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "gogive_routes",
+		Help: "Number of routes currently loaded from the config file.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(requestsTotal, routesGauge)
+}
@@ -0,0 +1,133 @@
+// Package proxy implements a fallback module proxy for gogive.
+//
+// A Proxy holds an ordered list of upstream GOPROXY servers, as described
+// at https://golang.org/ref/mod#goproxy-protocol. Requests are tried
+// against each upstream in turn, moving on to the next only when an
+// upstream answers with 404 or 410, matching the semantics the go command
+// itself uses for a comma-separated GOPROXY list.
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Direct is the pseudo-upstream name used by GOPROXY to mean "fetch the
+// module directly from its VCS instead of a proxy". gogive does not
+// implement direct VCS fetches, so a Proxy that reaches Direct in its
+// upstream list reports the module as not found.
+const Direct = "direct"
+
+// Proxy fans a GOPROXY request out across a list of upstream proxy
+// servers, returning the first response that is not a 404 or 410.
+type Proxy struct {
+	// Upstreams is the ordered list of proxy base URLs to try, as
+	// parsed from a route's configuration line. The special value
+	// Direct marks the end of the fallback chain.
+	Upstreams []string
+
+	// Client performs the outbound requests. If nil, http.DefaultClient
+	// is used.
+	Client *http.Client
+}
+
+// New returns a Proxy that tries upstreams in order.
+func New(upstreams []string) *Proxy {
+	return &Proxy{Upstreams: upstreams}
+}
+
+func (p *Proxy) client() *http.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	return http.DefaultClient
+}
+
+// Fetch requests path (e.g. "/example.com/foo/@v/list") from each
+// upstream in order, returning the first response whose status is not
+// 404 or 410. The caller is responsible for closing the returned
+// response's Body. If every upstream answers 404/410, the last such
+// response is returned.
+func (p *Proxy) Fetch(path string) (*http.Response, error) {
+	var last *http.Response
+	for _, u := range p.Upstreams {
+		if u == Direct {
+			if last != nil {
+				last.Body.Close()
+			}
+			last = &http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       io.NopCloser(strings.NewReader("gogive does not support GOPROXY=direct fallback\n")),
+			}
+			break
+		}
+		resp, err := p.client().Get(strings.TrimSuffix(u, "/") + path)
+		if err != nil {
+			if last != nil {
+				last.Body.Close()
+			}
+			return nil, fmt.Errorf("proxy %s: %w", u, err)
+		}
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+			if last != nil {
+				last.Body.Close()
+			}
+			last = resp
+			continue
+		}
+		if last != nil {
+			last.Body.Close()
+		}
+		return resp, nil
+	}
+	if last == nil {
+		return nil, fmt.Errorf("no upstream proxies configured")
+	}
+	return last, nil
+}
+
+// ServeHTTP implements the GOPROXY protocol for r.URL.Path, which must
+// already have the route's prefix stripped (e.g. "/example.com/foo/@v/list").
+// It tries each upstream in order and copies the first non-404/410
+// response to w.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	resp, err := p.Fetch(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// IsRequest reports whether path looks like a GOPROXY protocol request,
+// i.e. it addresses /@v/list, /@v/<version>.info|.mod|.zip, or /@latest.
+func IsRequest(path string) bool {
+	if strings.HasSuffix(path, "/@latest") {
+		return true
+	}
+	i := strings.LastIndex(path, "/@v/")
+	if i < 0 {
+		return false
+	}
+	file := path[i+len("/@v/"):]
+	if file == "list" {
+		return true
+	}
+	for _, ext := range []string{".info", ".mod", ".zip"} {
+		if len(file) > len(ext) && strings.HasSuffix(file, ext) && !strings.Contains(file, "/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// readTxtar loads a minimal txtar-style fixture: a sequence of
+//
+//	-- path --
+//	file contents...
+//
+// blocks, as used by goproxytest to lay out a fake module proxy on
+// disk without touching the network.
+func readTxtar(t *testing.T, name string) map[string]string {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	files := make(map[string]string)
+	var cur string
+	var body strings.Builder
+	flush := func() {
+		if cur != "" {
+			files[cur] = body.String()
+		}
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "-- ") && strings.HasSuffix(line, " --") {
+			flush()
+			cur = strings.TrimSuffix(strings.TrimPrefix(line, "-- "), " --")
+			body.Reset()
+			continue
+		}
+		body.WriteString(line)
+		body.WriteString("\n")
+	}
+	flush()
+	return files
+}
+
+// fixtureServer serves the files in a txtar fixture as a GOPROXY,
+// answering 404 for anything not present.
+func fixtureServer(t *testing.T, name string) *httptest.Server {
+	files := readTxtar(t, name)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(body))
+	}))
+}
+
+func TestFetchFirstUpstream(t *testing.T) {
+	up1 := fixtureServer(t, "testdata/upstream1.txtar")
+	defer up1.Close()
+	up2 := fixtureServer(t, "testdata/upstream2.txtar")
+	defer up2.Close()
+
+	p := New([]string{up1.URL, up2.URL})
+	resp, err := p.Fetch("/example.com/foo/@v/list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestFetchFallsThrough(t *testing.T) {
+	up1 := fixtureServer(t, "testdata/upstream1.txtar")
+	defer up1.Close()
+	up2 := fixtureServer(t, "testdata/upstream2.txtar")
+	defer up2.Close()
+
+	p := New([]string{up1.URL, up2.URL})
+	resp, err := p.Fetch("/example.com/bar/@v/list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestFetchNotFoundEverywhere(t *testing.T) {
+	up1 := fixtureServer(t, "testdata/upstream1.txtar")
+	defer up1.Close()
+
+	p := New([]string{up1.URL, Direct})
+	resp, err := p.Fetch("/example.com/missing/@v/list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestFetchNotFoundEverywhereKeepsLastBody(t *testing.T) {
+	notFound := func(msg string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, msg, http.StatusNotFound)
+		}))
+	}
+	up1 := notFound("upstream1: module not found")
+	defer up1.Close()
+	up2 := notFound("upstream2: module not found")
+	defer up2.Close()
+
+	p := New([]string{up1.URL, up2.URL})
+	resp, err := p.Fetch("/example.com/missing/@v/list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(body)); got != "upstream2: module not found" {
+		t.Errorf("body = %q, want the last upstream's error message", got)
+	}
+}
+
+func TestIsRequest(t *testing.T) {
+	cases := map[string]bool{
+		"/example.com/foo/@v/list":        true,
+		"/example.com/foo/@v/v1.0.0.info": true,
+		"/example.com/foo/@latest":        true,
+		"/example.com/foo":                false,
+	}
+	for path, want := range cases {
+		if got := IsRequest(path); got != want {
+			t.Errorf("IsRequest(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// routeNode is one segment of the route trie. A node may have an exact
+// child per path segment, plus at most one wildcard child that matches
+// any single segment.
+type routeNode struct {
+	children map[string]*routeNode
+	wildcard *routeNode
+	src      *Source
+}
+
+// Router resolves request paths to a Source by longest matching
+// prefix, backed by a trie rather than a flat map, so that a deeper
+// route (e.g. /foo/bar) always wins over a shallower one (/foo) when
+// both are configured. A path segment of "*" registers a wildcard
+// route; the segment it matches is substituted for "{1}" in the
+// matched Source's Url.
+type Router struct {
+	root *routeNode
+}
+
+func newRouter() *Router {
+	return &Router{root: &routeNode{}}
+}
+
+// Add registers src to serve path, which may end in a literal segment
+// or a single "*" wildcard segment (e.g. "/user/*"). It is an error to
+// Add the same path twice, or a path with more than one wildcard
+// segment: findPath only tracks the most recent wildcard match, so a
+// second "*" would silently discard the first segment it captured.
+func (rt *Router) Add(path string, src Source) error {
+	segs := splitPath(path)
+	wildcards := 0
+	for _, seg := range segs {
+		if seg == "*" {
+			wildcards++
+		}
+	}
+	if wildcards > 1 {
+		return fmt.Errorf("%s: route has %d wildcard segments, only one is supported", path, wildcards)
+	}
+
+	n := rt.root
+	for _, seg := range segs {
+		if seg == "*" {
+			if n.wildcard == nil {
+				n.wildcard = &routeNode{}
+			}
+			n = n.wildcard
+			continue
+		}
+		if n.children == nil {
+			n.children = make(map[string]*routeNode)
+		}
+		child, ok := n.children[seg]
+		if !ok {
+			child = &routeNode{}
+			n.children[seg] = child
+		}
+		n = child
+	}
+	if n.src != nil {
+		return fmt.Errorf("duplicate entry %s", path)
+	}
+	s := src
+	n.src = &s
+	return nil
+}
+
+// findPath resolves path to the Source registered for its longest
+// matching prefix, returning that prefix as root. If the match went
+// through a wildcard segment, "{1}" in the Source's Url is replaced
+// with the segment that matched it.
+func (rt *Router) findPath(path string) (Source, string, bool) {
+	n := rt.root
+	var built []string
+	var lastSrc *Source
+	var lastRoot string
+	var lastCapture string
+	var capture string
+
+	mark := func() {
+		if n.src != nil {
+			lastSrc = n.src
+			lastRoot = "/" + strings.Join(built, "/")
+			lastCapture = capture
+		}
+	}
+	mark()
+	for _, seg := range splitPath(path) {
+		switch {
+		case n.children[seg] != nil:
+			n = n.children[seg]
+			built = append(built, seg)
+		case n.wildcard != nil:
+			n = n.wildcard
+			built = append(built, seg)
+			capture = seg
+		default:
+			return derefOrZero(lastSrc, lastCapture), lastRoot, lastSrc != nil
+		}
+		mark()
+	}
+	return derefOrZero(lastSrc, lastCapture), lastRoot, lastSrc != nil
+}
+
+func derefOrZero(src *Source, capture string) Source {
+	if src == nil {
+		return Source{}
+	}
+	s := *src
+	if capture != "" {
+		s.Url = strings.ReplaceAll(s.Url, "{1}", capture)
+	}
+	return s
+}
+
+// splitPath splits path into its non-empty segments, ignoring leading,
+// trailing and repeated slashes.
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// RouteEntry is a flattened view of one registered route, as returned
+// by Router.Entries for the admin API.
+type RouteEntry struct {
+	Path    string `json:"path"`
+	Vcs     string `json:"vcs"`
+	Url     string `json:"url"`
+	Private bool   `json:"private,omitempty"`
+}
+
+// Entries returns every route registered in rt, sorted by path.
+func (rt *Router) Entries() []RouteEntry {
+	var out []RouteEntry
+	var walk func(n *routeNode, prefix []string)
+	walk = func(n *routeNode, prefix []string) {
+		if n.src != nil {
+			out = append(out, RouteEntry{
+				Path:    "/" + strings.Join(prefix, "/"),
+				Vcs:     n.src.Vcs,
+				Url:     n.src.Url,
+				Private: n.src.Private,
+			})
+		}
+		for seg, child := range n.children {
+			walk(child, append(append([]string{}, prefix...), seg))
+		}
+		if n.wildcard != nil {
+			walk(n.wildcard, append(append([]string{}, prefix...), "*"))
+		}
+	}
+	walk(rt.root, nil)
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
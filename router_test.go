@@ -0,0 +1,85 @@
+package main
+
+import "testing"
+
+func TestRouterOverlappingPrefixes(t *testing.T) {
+	r := newRouter()
+	must(t, r.Add("/foo", Source{Vcs: "git", Url: "https://example.com/foo.git"}))
+	must(t, r.Add("/foo/bar", Source{Vcs: "git", Url: "https://example.com/foo-bar.git"}))
+
+	if _, root, _ := r.findPath("/foo/quux"); root != "/foo" {
+		t.Errorf("findPath(/foo/quux) root = %q, want /foo", root)
+	}
+	if _, root, _ := r.findPath("/foo/bar/baz"); root != "/foo/bar" {
+		t.Errorf("findPath(/foo/bar/baz) root = %q, want /foo/bar", root)
+	}
+}
+
+func TestRouterTrailingSlash(t *testing.T) {
+	r := newRouter()
+	must(t, r.Add("/foo/", Source{Vcs: "git", Url: "https://example.com/foo.git"}))
+
+	if _, root, ok := r.findPath("/foo"); !ok || root != "/foo" {
+		t.Errorf("findPath(/foo) = %q, %v, want /foo, true", root, ok)
+	}
+	if _, root, ok := r.findPath("/foo/"); !ok || root != "/foo" {
+		t.Errorf("findPath(/foo/) = %q, %v, want /foo, true", root, ok)
+	}
+}
+
+func TestRouterWildcard(t *testing.T) {
+	r := newRouter()
+	must(t, r.Add("/user/*", Source{Vcs: "git", Url: "https://git.example.com/{1}.git"}))
+
+	src, root, ok := r.findPath("/user/alice/sub/pkg")
+	if !ok {
+		t.Fatal("findPath(/user/alice/sub/pkg) did not match")
+	}
+	if root != "/user/alice" {
+		t.Errorf("root = %q, want /user/alice", root)
+	}
+	if src.Url != "https://git.example.com/alice.git" {
+		t.Errorf("Url = %q, want https://git.example.com/alice.git", src.Url)
+	}
+}
+
+func TestRouterWildcardDoesNotLeakIntoShallowerMatch(t *testing.T) {
+	r := newRouter()
+	must(t, r.Add("/user", Source{Vcs: "git", Url: "https://git.example.com/user-{1}.git"}))
+	must(t, r.Add("/user/*/profile", Source{Vcs: "git", Url: "https://git.example.com/profile.git"}))
+
+	src, root, ok := r.findPath("/user/alice/other")
+	if !ok {
+		t.Fatal("findPath(/user/alice/other) did not match")
+	}
+	if root != "/user" {
+		t.Errorf("root = %q, want /user", root)
+	}
+	if src.Url != "https://git.example.com/user-{1}.git" {
+		t.Errorf("Url = %q, want https://git.example.com/user-{1}.git (unsubstituted)", src.Url)
+	}
+}
+
+func TestRouterRejectsMultipleWildcards(t *testing.T) {
+	r := newRouter()
+	err := r.Add("/a/*/b/*", Source{Vcs: "git", Url: "https://example.com/{1}.git"})
+	if err == nil {
+		t.Fatal("Add(/a/*/b/*) returned nil error, want error")
+	}
+}
+
+func TestRouterNoMatch(t *testing.T) {
+	r := newRouter()
+	must(t, r.Add("/foo", Source{Vcs: "git", Url: "https://example.com/foo.git"}))
+
+	if _, _, ok := r.findPath("/bar"); ok {
+		t.Error("findPath(/bar) matched, want no match")
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}